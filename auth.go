@@ -0,0 +1,35 @@
+package presto
+
+import "net/http"
+
+// Auth decorates outgoing requests with whatever credentials presto
+// requires. Authenticate is called before every request, including
+// retries, so implementations that need to refresh a token should do so
+// lazily rather than on construction.
+type Auth interface {
+	Authenticate(req *http.Request) error
+}
+
+// BasicAuth authenticates using HTTP Basic auth.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate sets the request's Authorization header via HTTP Basic auth.
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerAuth authenticates using a bearer token, such as a JWT issued by an
+// identity provider in front of presto.
+type BearerAuth struct {
+	Token string
+}
+
+// Authenticate sets the request's Authorization header to "Bearer <token>".
+func (a BearerAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
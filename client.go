@@ -0,0 +1,61 @@
+package presto
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Option configures a Query constructed by NewQuery or NewQueryContext.
+type Option func(*Query)
+
+// WithHTTPClient sets the *http.Client used for every request the query
+// makes, in place of http.DefaultClient. Use it to supply TLS
+// configuration, timeouts, or a proxy. Callers going through sql.Open
+// instead should register a named client with RegisterCustomClient and
+// select it via the custom_client DSN parameter.
+func WithHTTPClient(c *http.Client) Option {
+	return func(q *Query) {
+		q.httpClient = c
+	}
+}
+
+// WithAuth sets the Auth used to authenticate every request the query
+// makes.
+func WithAuth(auth Auth) Option {
+	return func(q *Query) {
+		q.auth = auth
+	}
+}
+
+var (
+	customClientsMu sync.RWMutex
+	customClients   = make(map[string]*http.Client)
+)
+
+// RegisterCustomClient registers an *http.Client under name, so that it can
+// be selected from a DSN via the custom_client parameter:
+//
+//	presto.RegisterCustomClient("insecure", &http.Client{
+//		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+//	})
+//	db, err := sql.Open("presto", "http://host:8080?custom_client=insecure")
+func RegisterCustomClient(name string, c *http.Client) error {
+	if name == "" {
+		return fmt.Errorf("presto: custom client name must not be empty")
+	}
+
+	customClientsMu.Lock()
+	defer customClientsMu.Unlock()
+	customClients[name] = c
+
+	return nil
+}
+
+func getCustomClient(name string) (*http.Client, bool) {
+	customClientsMu.RLock()
+	defer customClientsMu.RUnlock()
+
+	c, ok := customClients[name]
+	return c, ok
+}
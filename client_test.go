@@ -0,0 +1,49 @@
+package presto
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterCustomClient(t *testing.T) {
+	client := &http.Client{}
+	require.NoError(t, RegisterCustomClient("my-client", client))
+
+	got, ok := getCustomClient("my-client")
+	assert.True(t, ok)
+	assert.Same(t, client, got)
+
+	assert.Error(t, RegisterCustomClient("", client))
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts, err := optionsFromConfig(&Config{CustomClientName: "does-not-exist"})
+	assert.Error(t, err)
+	assert.Nil(t, opts)
+
+	client := &http.Client{}
+	require.NoError(t, RegisterCustomClient("configured", client))
+
+	opts, err = optionsFromConfig(&Config{CustomClientName: "configured"})
+	require.NoError(t, err)
+	require.Len(t, opts, 1)
+
+	q := &Query{}
+	opts[0](q)
+	assert.Same(t, client, q.httpClient)
+}
+
+func TestOptionsFromConfigSessionProperties(t *testing.T) {
+	opts, err := optionsFromConfig(&Config{
+		SessionProperties: map[string]string{"query_max_run_time": "1h"},
+	})
+	require.NoError(t, err)
+	require.Len(t, opts, 1)
+
+	q := &Query{}
+	opts[0](q)
+	assert.Equal(t, map[string]string{"query_max_run_time": "1h"}, q.session)
+}
@@ -0,0 +1,136 @@
+package presto
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryNextContextCanceled(t *testing.T) {
+	// A server that always reports the query as still running, with no
+	// data, so Next would otherwise poll forever.
+	canceled := make(chan struct{})
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/statement", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			close(canceled)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"q1","nextUri":"` + server.URL + `/v1/statement"}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q, err := NewQueryContext(ctx, server.URL, "test", "go-presto-test", "default", "default", "select 1")
+	require.NoError(t, err)
+
+	cancel()
+
+	_, err = q.NextContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("query was not canceled against the coordinator")
+	}
+}
+
+func TestQueryNextContextTimeoutDuringFetch(t *testing.T) {
+	// A coordinator that returns one page of real data, then hangs
+	// indefinitely on the next page fetch (but still answers a cancel
+	// promptly), simulating a stuck node.
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/statement", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"q1","nextUri":"` + server.URL + `/v1/statement/1"}`))
+	})
+	mux.HandleFunc("/v1/statement/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"q1","nextUri":"` + server.URL + `/v1/statement/2","columns":[{"name":"n","type":"bigint"}],"data":[[1]]}`))
+	})
+	mux.HandleFunc("/v1/statement/2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		<-r.Context().Done()
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "test", "go-presto-test", "default", "default", "select n")
+	require.NoError(t, err)
+	defer q.Close()
+
+	_, err = q.Next()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = q.NextContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestSetReadDeadlineBoundsOnlyOneRead(t *testing.T) {
+	var canceled bool
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/statement", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"q1","nextUri":"` + server.URL + `/v1/statement/1"}`))
+	})
+	mux.HandleFunc("/v1/statement/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"q1","nextUri":"` + server.URL + `/v1/statement/2","columns":[{"name":"n","type":"bigint"}],"data":[[1]]}`))
+	})
+	mux.HandleFunc("/v1/statement/2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			canceled = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// A slow page: slower than the read deadline below, but well within
+		// the test's own timeout, so the query should survive it.
+		time.Sleep(150 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"q1"}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "test", "go-presto-test", "default", "default", "select n")
+	require.NoError(t, err)
+	defer q.Close()
+
+	row, err := q.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("1")}, row)
+
+	q.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = q.NextContext(context.Background())
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.False(t, canceled, "a fired read deadline must not cancel the query")
+
+	// Without setting a new read deadline, this call is unbounded and
+	// should succeed once the slow page finally arrives.
+	row, err = q.Next()
+	require.NoError(t, err)
+	assert.Nil(t, row)
+}
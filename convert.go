@@ -0,0 +1,65 @@
+package presto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	prestoDateFormat      = "2006-01-02"
+	prestoTimestampFormat = "2006-01-02 15:04:05.000"
+)
+
+// convertValue converts a raw JSON-decoded value for a column of the given
+// Presto type into a database/sql/driver.Value.
+func convertValue(raw interface{}, prestoType string) (driver.Value, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	switch prestoType {
+	case "bigint", "integer", "smallint", "tinyint":
+		switch v := raw.(type) {
+		case json.Number:
+			return v.Int64()
+		case float64:
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case "double", "real":
+		switch v := raw.(type) {
+		case json.Number:
+			return v.Float64()
+		case float64:
+			return v, nil
+		}
+	case "boolean":
+		if v, ok := raw.(bool); ok {
+			return v, nil
+		}
+	case "date":
+		if v, ok := raw.(string); ok {
+			return time.Parse(prestoDateFormat, v)
+		}
+	case "timestamp":
+		if v, ok := raw.(string); ok {
+			return time.Parse(prestoTimestampFormat, v)
+		}
+	}
+
+	// varchar and char arrive as plain strings already; json, array, map,
+	// row, and anything else we don't have a native conversion for decode
+	// as their JSON representation.
+	if v, ok := raw.(string); ok {
+		return v, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
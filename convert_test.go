@@ -0,0 +1,74 @@
+package presto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertValueNull(t *testing.T) {
+	v, err := convertValue(nil, "bigint")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestConvertValueBigintBeyondFloat64Range(t *testing.T) {
+	// math.MaxInt64, which is not exactly representable as a float64: a
+	// naive round-trip through float64 flips its sign.
+	v, err := convertValue(json.Number("9223372036854775807"), "bigint")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9223372036854775807), v)
+}
+
+func TestConvertValueIntegerTypes(t *testing.T) {
+	for _, prestoType := range []string{"bigint", "integer", "smallint", "tinyint"} {
+		v, err := convertValue(json.Number("42"), prestoType)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), v)
+	}
+}
+
+func TestConvertValueDouble(t *testing.T) {
+	v, err := convertValue(json.Number("3.5"), "double")
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, v)
+
+	v, err = convertValue(json.Number("3.5"), "real")
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, v)
+}
+
+func TestConvertValueBoolean(t *testing.T) {
+	v, err := convertValue(true, "boolean")
+	require.NoError(t, err)
+	assert.Equal(t, true, v)
+}
+
+func TestConvertValueDateAndTimestamp(t *testing.T) {
+	v, err := convertValue("2020-01-02", "date")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), v)
+
+	v, err = convertValue("2020-01-02 03:04:05.000", "timestamp")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), v)
+}
+
+func TestConvertValueVarchar(t *testing.T) {
+	v, err := convertValue("hello", "varchar")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", v)
+}
+
+func TestConvertValueComplexTypesFallBackToJSON(t *testing.T) {
+	v, err := convertValue([]interface{}{json.Number("1"), json.Number("2"), json.Number("3")}, "array(bigint)")
+	require.NoError(t, err)
+	assert.Equal(t, "[1,2,3]", v)
+
+	v, err = convertValue(map[string]interface{}{"a": json.Number("1")}, "map(varchar,bigint)")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, v)
+}
@@ -0,0 +1,55 @@
+package presto
+
+import (
+	"sync"
+	"time"
+)
+
+// deadlineTimer is a resettable one-shot deadline: a single monotonic timer
+// that closes a channel when it fires, modeled on the deadlineTimer used
+// throughout netstack's tcpip endpoints. It lets callers bound how long a
+// Query waits between retries or polls without needing a context.Context.
+type deadlineTimer struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+}
+
+// set arms the deadline for t. A zero t disables it. set may be called
+// repeatedly to move the deadline.
+func (d *deadlineTimer) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The channel is kept, so a goroutine already waiting on it sees the
+	// moved deadline, unless an earlier deadline has already closed it.
+	if d.timer != nil && !d.timer.Stop() {
+		d.done = nil
+	}
+
+	if d.done == nil {
+		d.done = make(chan struct{})
+	}
+	if t.IsZero() {
+		d.timer = nil
+		return
+	}
+
+	done := d.done
+	d.timer = time.AfterFunc(time.Until(t), func() {
+		close(done)
+	})
+}
+
+// doneCh returns the channel that's closed when the deadline fires. It is
+// never nil, even before any deadline has been set.
+func (d *deadlineTimer) doneCh() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done == nil {
+		d.done = make(chan struct{})
+	}
+
+	return d.done
+}
@@ -0,0 +1,165 @@
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+func init() {
+	sql.Register("presto", &Driver{})
+}
+
+// Driver implements database/sql/driver.Driver, so that presto can be used
+// as a database/sql driver via sql.Open("presto", dsn).
+type Driver struct{}
+
+// Open parses dsn and returns a new connection. Presto has no persistent
+// server-side connection state, so the returned Conn just remembers the
+// parsed DSN until a query is run against it.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := optionsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{cfg: cfg, opts: opts}, nil
+}
+
+// optionsFromConfig translates the auth- and transport-related fields of a
+// parsed DSN into Options for NewQueryContext.
+func optionsFromConfig(cfg *Config) ([]Option, error) {
+	var opts []Option
+
+	switch {
+	case cfg.CustomClientName != "":
+		c, ok := getCustomClient(cfg.CustomClientName)
+		if !ok {
+			return nil, fmt.Errorf("presto: no custom client registered as %q", cfg.CustomClientName)
+		}
+		opts = append(opts, WithHTTPClient(c))
+	case cfg.SSLCertPath != "":
+		c, err := sslClient(cfg.SSLCertPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithHTTPClient(c))
+	}
+
+	if cfg.KerberosPrincipal != "" || cfg.KerberosRealm != "" || cfg.KerberosConfigPath != "" || cfg.KerberosKeytabPath != "" {
+		opts = append(opts, WithAuth(&KerberosAuth{
+			Principal:  cfg.KerberosPrincipal,
+			Realm:      cfg.KerberosRealm,
+			ConfigPath: cfg.KerberosConfigPath,
+			KeytabPath: cfg.KerberosKeytabPath,
+		}))
+	}
+
+	if len(cfg.SessionProperties) > 0 {
+		opts = append(opts, WithSessionProperties(cfg.SessionProperties))
+	}
+
+	return opts, nil
+}
+
+// Conn implements driver.Conn.
+type Conn struct {
+	cfg  *Config
+	opts []Option
+}
+
+// Prepare returns a statement bound to query. Presto doesn't support
+// parameterized queries over the HTTP API, so args passed to the returned
+// Stmt are rejected.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return &Stmt{conn: c, query: query}, nil
+}
+
+// Close is a no-op, since a Conn holds no resources between queries.
+func (c *Conn) Close() error {
+	return nil
+}
+
+// Begin is unsupported: presto has no notion of transactions.
+func (c *Conn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("presto: transactions are not supported")
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("presto: parameterized queries are not supported")
+	}
+
+	q, err := NewQueryContext(ctx, c.cfg.Host, c.cfg.User, c.cfg.Source, c.cfg.Catalog, c.cfg.Schema, query, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{q: q}, nil
+}
+
+// ExecContext implements driver.ExecerContext by running the query to
+// completion and discarding the rows, since presto has no notion of rows
+// affected.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, len(rows.Columns()))
+	for {
+		err := rows.Next(dest)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+// Stmt implements driver.Stmt for a single query string bound to a Conn.
+type Stmt struct {
+	conn  *Conn
+	query string
+}
+
+// Close is a no-op.
+func (s *Stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1, since presto statements aren't yet parameterized.
+func (s *Stmt) NumInput() int {
+	return -1
+}
+
+// Exec runs the statement via the connection's ExecContext.
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+// Query runs the statement via the connection's QueryContext.
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+
+	return named
+}
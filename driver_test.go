@@ -0,0 +1,159 @@
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dsnFor builds a DSN pointing at server, with a catalog/schema set so
+// queries don't need to specify one.
+func dsnFor(server string) string {
+	return "http://user@" + strings.TrimPrefix(server, "http://") + "?catalog=default&schema=default"
+}
+
+// TestDriverQueryContext exercises the full database/sql path: sql.Open,
+// QueryContext against a fake coordinator, and Rows.Next/Scan, including a
+// bigint beyond float64's safe-integer range, a null, and an array value
+// that falls through to the JSON-string encoding.
+func TestDriverQueryContext(t *testing.T) {
+	pages := []fakePage{
+		{ID: "q1", NextUri: "/v1/statement/1"},
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/2",
+			Columns: []fakeColumn{
+				{Name: "n", Type: "bigint"},
+				{Name: "label", Type: "varchar"},
+				{Name: "tags", Type: "array(varchar)"},
+			},
+			Data: [][]interface{}{
+				{9223372036854775807, "hello", []interface{}{"a", "b"}},
+			},
+		},
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/3",
+			Data: [][]interface{}{
+				{nil, nil, nil},
+			},
+		},
+		{ID: "q1"},
+	}
+
+	server := newFakePresto(t, pages)
+	defer server.Close()
+
+	db, err := sql.Open("presto", dsnFor(server.URL))
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "select n, label, tags")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"n", "label", "tags"}, cols)
+
+	require.True(t, rows.Next())
+	var n int64
+	var label string
+	var tags string
+	require.NoError(t, rows.Scan(&n, &label, &tags))
+	assert.Equal(t, int64(9223372036854775807), n)
+	assert.Equal(t, "hello", label)
+	assert.Equal(t, `["a","b"]`, tags)
+
+	require.True(t, rows.Next())
+	var nNull sql.NullInt64
+	var labelNull sql.NullString
+	var tagsNull sql.NullString
+	require.NoError(t, rows.Scan(&nNull, &labelNull, &tagsNull))
+	assert.False(t, nNull.Valid)
+	assert.False(t, labelNull.Valid)
+	assert.False(t, tagsNull.Valid)
+
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+}
+
+// TestDriverExecContext exercises ExecContext, which runs a query to
+// completion and discards the rows.
+func TestDriverExecContext(t *testing.T) {
+	pages := []fakePage{
+		{ID: "q1", NextUri: "/v1/statement/1"},
+		{ID: "q1"},
+	}
+
+	server := newFakePresto(t, pages)
+	defer server.Close()
+
+	db, err := sql.Open("presto", dsnFor(server.URL))
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := db.ExecContext(context.Background(), "create table foo (n bigint)")
+	require.NoError(t, err)
+
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+}
+
+func TestDriverOpenInvalidDSN(t *testing.T) {
+	db, err := sql.Open("presto", "://not-a-valid-dsn")
+	require.NoError(t, err) // sql.Open doesn't dial; the DSN is only parsed on first use.
+	defer db.Close()
+
+	assert.Error(t, db.PingContext(context.Background()))
+}
+
+func TestConnPrepareAndQuery(t *testing.T) {
+	pages := []fakePage{
+		{ID: "q1", NextUri: "/v1/statement/1"},
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/2",
+			Columns: []fakeColumn{{Name: "n", Type: "bigint"}},
+			Data:    [][]interface{}{{1}},
+		},
+		{ID: "q1"},
+	}
+
+	server := newFakePresto(t, pages)
+	defer server.Close()
+
+	db, err := sql.Open("presto", dsnFor(server.URL))
+	require.NoError(t, err)
+	defer db.Close()
+
+	stmt, err := db.PrepareContext(context.Background(), "select n")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(context.Background())
+	require.NoError(t, err)
+	defer rows.Close()
+
+	require.True(t, rows.Next())
+	var n int64
+	require.NoError(t, rows.Scan(&n))
+	assert.Equal(t, int64(1), n)
+}
+
+func TestConnBeginUnsupported(t *testing.T) {
+	server := newFakePresto(t, nil)
+	defer server.Close()
+
+	db, err := sql.Open("presto", dsnFor(server.URL))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Begin()
+	assert.Error(t, err)
+}
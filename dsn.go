@@ -0,0 +1,79 @@
+package presto
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Config holds the fields parsed out of a presto data source name, as
+// accepted by sql.Open("presto", dsn). A DSN looks like:
+//
+//	http://user@host:8080?catalog=hive&schema=default
+type Config struct {
+	Host              string
+	User              string
+	Catalog           string
+	Schema            string
+	Source            string
+	SessionProperties map[string]string
+	CustomClientName  string
+
+	// SSLCertPath, if set, is a PEM file of CA certificates to trust in
+	// place of the system roots: only the certificates in the file are
+	// trusted.
+	SSLCertPath string
+
+	// KerberosPrincipal, KerberosRealm, KerberosConfigPath, and
+	// KerberosKeytabPath configure Kerberos/SPNEGO authentication, which is
+	// enabled as soon as any of them is set. All four are needed for the
+	// login to succeed.
+	KerberosPrincipal  string
+	KerberosRealm      string
+	KerberosConfigPath string
+	KerberosKeytabPath string
+}
+
+// ParseDSN parses a presto data source name into a Config.
+func ParseDSN(dsn string) (*Config, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("presto: invalid dsn: %s", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("presto: invalid dsn scheme: %q", u.Scheme)
+	}
+
+	cfg := &Config{
+		Host: fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+	}
+
+	if u.User != nil {
+		cfg.User = u.User.Username()
+	}
+
+	query := u.Query()
+	cfg.Catalog = query.Get("catalog")
+	cfg.Schema = query.Get("schema")
+	cfg.Source = query.Get("source")
+	cfg.CustomClientName = query.Get("custom_client")
+	cfg.SSLCertPath = query.Get("ssl_cert_path")
+	cfg.KerberosPrincipal = query.Get("kerberos_principal")
+	cfg.KerberosRealm = query.Get("kerberos_realm")
+	cfg.KerberosConfigPath = query.Get("kerberos_config_path")
+	cfg.KerberosKeytabPath = query.Get("kerberos_keytab_path")
+
+	if props := query.Get("session_properties"); props != "" {
+		cfg.SessionProperties = make(map[string]string)
+		for _, kv := range strings.Split(props, ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("presto: invalid session property: %q", kv)
+			}
+			cfg.SessionProperties[parts[0]] = parts[1]
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,25 @@
+package presto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSN(t *testing.T) {
+	cfg, err := ParseDSN("http://bob@presto.example.com:8080?catalog=hive&schema=default&session_properties=query_max_run_time=1h,foo=bar&custom_client=noredirect")
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://presto.example.com:8080", cfg.Host)
+	assert.Equal(t, "bob", cfg.User)
+	assert.Equal(t, "hive", cfg.Catalog)
+	assert.Equal(t, "default", cfg.Schema)
+	assert.Equal(t, "noredirect", cfg.CustomClientName)
+	assert.Equal(t, map[string]string{"query_max_run_time": "1h", "foo": "bar"}, cfg.SessionProperties)
+}
+
+func TestParseDSNInvalidScheme(t *testing.T) {
+	_, err := ParseDSN("ftp://presto.example.com:8080")
+	assert.Error(t, err)
+}
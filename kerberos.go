@@ -0,0 +1,77 @@
+package presto
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"gopkg.in/jcmturner/gokrb5.v7/client"
+	"gopkg.in/jcmturner/gokrb5.v7/config"
+	"gopkg.in/jcmturner/gokrb5.v7/keytab"
+	"gopkg.in/jcmturner/gokrb5.v7/spnego"
+)
+
+// KerberosAuth authenticates via Kerberos/SPNEGO, for presto clusters
+// fronted by a kerberized load balancer. The krb5 client is logged in
+// lazily on the first request and reused for subsequent ones.
+type KerberosAuth struct {
+	// Principal and Realm identify the client principal to authenticate
+	// as.
+	Principal string
+	Realm     string
+
+	// ConfigPath is the path to a krb5.conf file.
+	ConfigPath string
+
+	// KeytabPath is the path to a keytab containing the principal's keys.
+	KeytabPath string
+
+	// ServiceName is the service principal name presto is registered
+	// under. If empty, it defaults to "HTTP@<request host>".
+	ServiceName string
+
+	mu sync.Mutex
+	cl *client.Client
+}
+
+// Authenticate sets the request's SPNEGO Authorization header.
+func (a *KerberosAuth) Authenticate(req *http.Request) error {
+	cl, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	spn := a.ServiceName
+	if spn == "" {
+		spn = "HTTP@" + req.URL.Hostname()
+	}
+
+	return spnego.SetSPNEGOHeader(cl, req, spn)
+}
+
+func (a *KerberosAuth) client() (*client.Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cl != nil {
+		return a.cl, nil
+	}
+
+	cfg, err := config.Load(a.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("presto: loading krb5 config: %s", err)
+	}
+
+	kt, err := keytab.Load(a.KeytabPath)
+	if err != nil {
+		return nil, fmt.Errorf("presto: loading keytab: %s", err)
+	}
+
+	cl := client.NewClientWithKeytab(a.Principal, a.Realm, kt, cfg)
+	if err := cl.Login(); err != nil {
+		return nil, fmt.Errorf("presto: kerberos login: %s", err)
+	}
+
+	a.cl = cl
+	return a.cl, nil
+}
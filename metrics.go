@@ -0,0 +1,63 @@
+package presto
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// retryMetrics holds the collectors used to report retry behavior to
+// prometheus. It's nil on a Query unless WithMetrics is used.
+type retryMetrics struct {
+	retriesTotal  prometheus.Counter
+	retriesByCode *prometheus.CounterVec
+	retryLatency  prometheus.Histogram
+}
+
+var (
+	retryMetricsMu    sync.Mutex
+	retryMetricsByReg = map[prometheus.Registerer]*retryMetrics{}
+)
+
+// newRetryMetrics returns the retryMetrics registered with reg, registering
+// and caching a new one on first use. Every Query created with
+// WithMetrics(reg) for the same reg shares the same collectors, so
+// registering a second query against a registry already used by another
+// doesn't panic with a duplicate-registration error.
+func newRetryMetrics(reg prometheus.Registerer) *retryMetrics {
+	retryMetricsMu.Lock()
+	defer retryMetricsMu.Unlock()
+
+	if m, ok := retryMetricsByReg[reg]; ok {
+		return m
+	}
+
+	m := &retryMetrics{
+		retriesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "presto_client_retries_total",
+			Help: "Total number of HTTP requests retried against presto.",
+		}),
+		retriesByCode: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "presto_client_retries_by_status_total",
+			Help: "Number of retried requests against presto, by HTTP status code.",
+		}, []string{"code"}),
+		retryLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "presto_client_retry_latency_seconds",
+			Help:    "Time spent backing off between retried requests.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+
+	reg.MustRegister(m.retriesTotal, m.retriesByCode, m.retryLatency)
+	retryMetricsByReg[reg] = m
+	return m
+}
+
+// WithMetrics registers retry counters and a retry-latency histogram with
+// reg, so operators can monitor how often requests to presto are being
+// retried. It's safe to use with more than one Query against the same reg.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(q *Query) {
+		q.retryMetrics = newRetryMetrics(reg)
+	}
+}
@@ -0,0 +1,193 @@
+package presto
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type fakePage struct {
+	ID      string                 `json:"id"`
+	NextUri string                 `json:"nextUri"`
+	Data    [][]interface{}        `json:"data"`
+	Columns []fakeColumn           `json:"columns,omitempty"`
+	Stats   map[string]interface{} `json:"stats,omitempty"`
+}
+
+// newFakePresto serves a canned, paginated query result, mimicking just
+// enough of the presto statement protocol to exercise the fetch/decode
+// pipeline: the first request goes to /v1/statement, and each page's
+// nextUri points at the following page served from /v1/statement/<n>.
+func newFakePresto(t *testing.T, pages []fakePage) *httptest.Server {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+
+	for i, page := range pages {
+		i, page := i, page
+		path := "/v1/statement"
+		if i > 0 {
+			path = fmt.Sprintf("/v1/statement/%d", i)
+		}
+
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			resp := page
+			if resp.NextUri != "" {
+				resp.NextUri = server.URL + resp.NextUri
+			}
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(resp))
+		})
+	}
+
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestQueryNextPipeline(t *testing.T) {
+	pages := []fakePage{
+		// The initial POST response: just an acknowledgement, no data yet.
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/1",
+		},
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/2",
+			Columns: []fakeColumn{{Name: "n", Type: "bigint"}},
+			Data:    [][]interface{}{{float64(1)}},
+		},
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/3",
+			Data:    [][]interface{}{{float64(2)}},
+		},
+		{
+			ID: "q1",
+		},
+	}
+
+	server := newFakePresto(t, pages)
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "test", "go-presto-test", "default", "default", "select n")
+	require.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, []string{"n"}, q.Columns())
+
+	row, err := q.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("1")}, row)
+
+	row, err = q.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("2")}, row)
+
+	row, err = q.Next()
+	require.NoError(t, err)
+	assert.Nil(t, row)
+}
+
+func TestQueryStats(t *testing.T) {
+	pages := []fakePage{
+		{ID: "q1", NextUri: "/v1/statement/1"},
+		{
+			ID:      "q1",
+			NextUri: "/v1/statement/2",
+			Columns: []fakeColumn{{Name: "n", Type: "bigint"}},
+			Data:    [][]interface{}{{float64(1)}},
+			Stats: map[string]interface{}{
+				"state":             "RUNNING",
+				"scheduled":         true,
+				"queuedSplits":      1,
+				"runningSplits":     2,
+				"completedSplits":   3,
+				"totalSplits":       6,
+				"elapsedTimeMillis": 100,
+				"cpuTimeMillis":     50,
+				"processedRows":     10,
+				"processedBytes":    1024,
+				"peakMemoryBytes":   2048,
+			},
+		},
+		{ID: "q1"},
+	}
+
+	server := newFakePresto(t, pages)
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "test", "go-presto-test", "default", "default", "select n")
+	require.NoError(t, err)
+	defer q.Close()
+
+	info := <-q.Stats()
+	assert.Equal(t, "RUNNING", info.State)
+	assert.Equal(t, 0.5, info.Progress)
+	assert.Equal(t, 1, info.QueuedSplits)
+	assert.Equal(t, 2, info.RunningSplits)
+	assert.Equal(t, int64(100), info.ElapsedTimeMillis)
+	assert.Equal(t, int64(50), info.CPUTimeMillis)
+	assert.Equal(t, int64(10), info.ProcessedRows)
+	assert.Equal(t, int64(1024), info.ProcessedBytes)
+	assert.Equal(t, int64(2048), info.PeakMemoryBytes)
+
+	_, err = q.Next()
+	require.NoError(t, err)
+	_, err = q.Next()
+	require.NoError(t, err)
+
+	require.NoError(t, q.Close())
+	for range q.Stats() {
+	}
+}
+
+// TestQueryPollBackoffUsesRetryPolicy asserts that decodeLoop's backoff
+// between polls of an empty, not-yet-complete page derives from
+// WithRetryPolicy rather than the package's hardcoded default bounds: a
+// query configured with a much smaller policy should run through several
+// empty pages well within the default policy's MaxBackoff.
+func TestQueryPollBackoffUsesRetryPolicy(t *testing.T) {
+	pages := []fakePage{
+		{ID: "q1", NextUri: "/v1/statement/1"},
+		{ID: "q1", NextUri: "/v1/statement/2"},
+		{ID: "q1", NextUri: "/v1/statement/3"},
+		{ID: "q1", NextUri: "/v1/statement/4"},
+		{ID: "q1", NextUri: "/v1/statement/5"},
+		{
+			ID:      "q1",
+			Columns: []fakeColumn{{Name: "n", Type: "bigint"}},
+			Data:    [][]interface{}{{1}},
+		},
+	}
+
+	server := newFakePresto(t, pages)
+	defer server.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 2 * time.Millisecond
+	policy.JitterFactor = 0
+
+	q, err := NewQuery(server.URL, "test", "go-presto-test", "default", "default", "select n",
+		WithRetryPolicy(policy))
+	require.NoError(t, err)
+	defer q.Close()
+
+	start := time.Now()
+	row, err := q.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("1")}, row)
+	assert.Less(t, time.Since(start), maxRetry,
+		"polling should pace by the query's own retry policy, not the package default")
+}
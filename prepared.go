@@ -0,0 +1,110 @@
+package presto
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PreparedQuery is a named statement registered with presto via
+// PrepareContext, which can be run (with parameters) any number of times via
+// Execute.
+type PreparedQuery struct {
+	q    *Query
+	name string
+	sql  string
+}
+
+// PrepareContext registers sql as a named prepared statement, using the
+// query's existing host, user, and session. The returned PreparedQuery can
+// be run, with parameters, via Execute/ExecuteContext.
+func (q *Query) PrepareContext(ctx context.Context, name, sql string) (*PreparedQuery, error) {
+	stmt := fmt.Sprintf("PREPARE %s FROM %s", name, sql)
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/v1/statement", q.host), strings.NewReader(stmt))
+	if _, err := q.fetchResult(ctx, req); err != nil {
+		return nil, fmt.Errorf("presto: preparing statement %q: %s", name, err)
+	}
+
+	return &PreparedQuery{q: q, name: name, sql: sql}, nil
+}
+
+// Prepare is equivalent to PrepareContext with context.Background().
+func (q *Query) Prepare(name, sql string) (*PreparedQuery, error) {
+	return q.PrepareContext(context.Background(), name, sql)
+}
+
+// Execute is equivalent to ExecuteContext with context.Background().
+func (p *PreparedQuery) Execute(args ...interface{}) (*Query, error) {
+	return p.ExecuteContext(context.Background(), args...)
+}
+
+// ExecuteContext runs the prepared statement, substituting args as typed
+// literals into an EXECUTE ... USING clause, and returns a new Query over
+// the results. The new query inherits the preparing query's host, source,
+// catalog, schema, session, HTTP client, and auth.
+func (p *PreparedQuery) ExecuteContext(ctx context.Context, args ...interface{}) (*Query, error) {
+	literals := make([]string, len(args))
+	for i, arg := range args {
+		lit, err := literal(arg)
+		if err != nil {
+			return nil, err
+		}
+		literals[i] = lit
+	}
+
+	stmt := "EXECUTE " + p.name
+	if len(literals) > 0 {
+		stmt += " USING " + strings.Join(literals, ", ")
+	}
+
+	q := p.q
+	opts := []Option{withPreparedStatement(p.name, p.sql)}
+	if session := q.sessionSnapshot(); session != nil {
+		opts = append(opts, WithSessionProperties(session))
+	}
+	if q.httpClient != nil {
+		opts = append(opts, WithHTTPClient(q.httpClient))
+	}
+	if q.auth != nil {
+		opts = append(opts, WithAuth(q.auth))
+	}
+
+	return NewQueryContext(ctx, q.host, q.user, q.source, q.catalog, q.schema, stmt, opts...)
+}
+
+// withPreparedStatement arranges for every request the query makes to carry
+// the X-Presto-Prepared-Statement header, so the (stateless) coordinator can
+// resolve name against sql.
+func withPreparedStatement(name, sql string) Option {
+	return func(q *Query) {
+		q.preparedName = name
+		q.preparedSQL = sql
+	}
+}
+
+// literal renders v as a presto SQL literal, for substitution into an
+// EXECUTE ... USING clause.
+func literal(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float32, float64:
+		return fmt.Sprintf("%v", v), nil
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case time.Time:
+		return fmt.Sprintf("TIMESTAMP '%s'", v.Format(prestoTimestampFormat)), nil
+	default:
+		return "", fmt.Errorf("presto: unsupported argument type %T", v)
+	}
+}
@@ -0,0 +1,69 @@
+package presto
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareExecute(t *testing.T) {
+	var gotQueries []string
+	var gotPreparedHeader string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, r.ContentLength)
+		r.Body.Read(body)
+		gotQueries = append(gotQueries, string(body))
+		gotPreparedHeader = r.Header.Get(preparedStatementHeader)
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "q1",
+			"nextUri": "",
+			"stats":   map[string]interface{}{"state": "FINISHED"},
+		}))
+	}))
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "user", "test", "catalog", "schema", "SELECT 1")
+	require.NoError(t, err)
+	defer q.Close()
+
+	prepared, err := q.PrepareContext(context.Background(), "my_stmt", "SELECT * FROM t WHERE id = ?")
+	require.NoError(t, err)
+	assert.Equal(t, "PREPARE my_stmt FROM SELECT * FROM t WHERE id = ?", gotQueries[len(gotQueries)-1])
+
+	exec, err := prepared.Execute(42, "foo")
+	require.NoError(t, err)
+	defer exec.Close()
+
+	assert.Equal(t, "EXECUTE my_stmt USING 42, 'foo'", gotQueries[len(gotQueries)-1])
+	assert.Equal(t, "my_stmt=SELECT+%2A+FROM+t+WHERE+id+%3D+%3F", gotPreparedHeader)
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, "NULL"},
+		{true, "true"},
+		{42, "42"},
+		{3.5, "3.5"},
+		{"it's", "'it''s'"},
+	}
+
+	for _, tt := range tests {
+		got, err := literal(tt.in)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := literal(struct{}{})
+	assert.Error(t, err)
+}
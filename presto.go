@@ -13,4 +13,9 @@ const (
 	schemaHeader    = "X-Presto-Schema"
 	userAgentHeader = "User-Agent"
 	userAgent       = "go-presto/" + version
+
+	sessionHeader           = "X-Presto-Session"
+	setSessionHeader        = "X-Presto-Set-Session"
+	clearSessionHeader      = "X-Presto-Clear-Session"
+	preparedStatementHeader = "X-Presto-Prepared-Statement"
 )
@@ -1,11 +1,16 @@
 package presto
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,6 +18,10 @@ const (
 	initialRetry            = 50 * time.Millisecond
 	maxRetry                = 800 * time.Millisecond
 	ProgressUnknown float64 = -1.0
+
+	// defaultBufferSize is the default capacity of a Query's fetch and
+	// response channels.
+	defaultBufferSize = 10
 )
 
 // Query represents an open query to presto.
@@ -28,10 +37,100 @@ type Query struct {
 	id      string
 	columns []string
 
+	// fullyClosed guards Close, so it still tears down the pipeline and
+	// closes statsCh exactly once even if the query was already exhausted
+	// (closed) naturally via Next.
+	fullyClosed bool
+
+	// columnTypes holds the presto type (e.g. "bigint", "varchar") for each
+	// entry in columns, in the same order, used to convert row values into
+	// driver.Value in the database/sql driver.
+	columnTypes []string
+
 	bufferedRows [][]interface{}
 	state        string
 	progress     float64
 	nextUri      string
+
+	// FetchBufferSize and ResponseBufferSize control how many outstanding
+	// HTTP responses and decoded result pages, respectively, the fetcher and
+	// decoder goroutines are allowed to buffer ahead of Next. Both default
+	// to 10.
+	FetchBufferSize    int
+	ResponseBufferSize int
+
+	// ctx is the context the query was created with. It governs the
+	// lifetime of the fetcher and decoder goroutines, in addition to
+	// whatever per-call context is passed to NextContext.
+	ctx context.Context
+
+	// pipelineCancel cancels the context passed to the fetcher and decoder
+	// goroutines (and, transitively, to every request they make via
+	// makeRequest). It's called from drainPipeline and from the deadline
+	// watcher started in startPipeline, so a request blocked in
+	// httpClient.Do is aborted immediately instead of only being noticed
+	// the next time the goroutine checks between requests.
+	pipelineCancel context.CancelFunc
+
+	deadline     deadlineTimer
+	readDeadline deadlineTimer
+
+	// httpClient is used for every request the query makes, defaulting to
+	// http.DefaultClient. See WithHTTPClient.
+	httpClient *http.Client
+
+	// auth, if set, authenticates every request the query makes. See
+	// WithAuth.
+	auth Auth
+
+	retryPolicy  RetryPolicy
+	retryMetrics *retryMetrics
+
+	// session holds the current presto session properties, sent with every
+	// request via the X-Presto-Session header and kept up to date from the
+	// X-Presto-Set-Session/X-Presto-Clear-Session response headers. It's
+	// written by the decoder goroutine (applySessionHeaders) and read by
+	// the fetcher goroutine (makeRequest) and by the user's goroutine
+	// (SetSession, PrepareContext/ExecuteContext), so sessionMu guards
+	// every access. See SetSession.
+	sessionMu sync.Mutex
+	session   map[string]string
+
+	// preparedName and preparedSQL, if set, are sent with every request via
+	// the X-Presto-Prepared-Statement header, so the (stateless) coordinator
+	// can associate an EXECUTE statement with the statement it names. See
+	// PrepareContext.
+	preparedName string
+	preparedSQL  string
+
+	fetchCh    chan *http.Response
+	responseCh chan *queryResult
+	nextUriCh  chan string
+	errCh      chan error
+	doneCh     chan struct{}
+	doneOnce   sync.Once
+
+	// statsCh carries a QueryProgressInfo snapshot every time the pipeline
+	// decodes a page of results. See Stats.
+	statsCh chan QueryProgressInfo
+}
+
+// queryStats is the "stats" object presto includes with every page of
+// results, describing the query's scheduling state and resource usage so
+// far.
+type queryStats struct {
+	State           string `json:"state"`
+	Scheduled       bool   `json:"scheduled"`
+	QueuedSplits    int    `json:"queuedSplits"`
+	RunningSplits   int    `json:"runningSplits"`
+	CompletedSplits int    `json:"completedSplits"`
+	TotalSplits     int    `json:"totalSplits"`
+
+	ElapsedTimeMillis int64 `json:"elapsedTimeMillis"`
+	CPUTimeMillis     int64 `json:"cpuTimeMillis"`
+	ProcessedRows     int64 `json:"processedRows"`
+	ProcessedBytes    int64 `json:"processedBytes"`
+	PeakMemoryBytes   int64 `json:"peakMemoryBytes"`
 }
 
 type queryResult struct {
@@ -41,7 +140,12 @@ type queryResult struct {
 	PartialCancelUri string          `json:"PartialCancelUri"`
 	Data             [][]interface{} `json:"data"`
 	Columns          []struct {
-		Name string `json:"name"`
+		Name          string `json:"name"`
+		Type          string `json:"type"`
+		TypeSignature struct {
+			RawType       string        `json:"rawType"`
+			TypeArguments []interface{} `json:"typeArguments"`
+		} `json:"typeSignature"`
 	} `json:"columns"`
 	Error struct {
 		ErrorCode   int `json:"errorCode"`
@@ -49,15 +153,19 @@ type queryResult struct {
 			Message string `json:"message"`
 		} `json:"failureInfo"`
 	} `json:"error"`
-	Stats struct {
-		State           string `json:"state"`
-		Scheduled       bool   `json:"scheduled"`
-		CompletedSplits int    `json:"completedSplits"`
-		TotalSplits     int    `json:"totalSplits"`
-	} `json:"stats"`
+	Stats queryStats `json:"stats"`
+}
+
+// NewQuery is equivalent to NewQueryContext with context.Background().
+func NewQuery(host, user, source, catalog, schema, query string, opts ...Option) (*Query, error) {
+	return NewQueryContext(context.Background(), host, user, source, catalog, schema, query, opts...)
 }
 
-func NewQuery(host, user, source, catalog, schema, query string) (*Query, error) {
+// NewQueryContext opens a new query against presto. ctx governs the
+// lifetime of the query: canceling it, or letting its deadline pass, stops
+// the background fetch-and-decode pipeline and issues a best-effort cancel
+// against presto's nextUri.
+func NewQueryContext(ctx context.Context, host, user, source, catalog, schema, query string, opts ...Option) (*Query, error) {
 	if user == "" {
 		user = "anonymous"
 	}
@@ -81,22 +189,61 @@ func NewQuery(host, user, source, catalog, schema, query string) (*Query, error)
 		catalog: catalog,
 		schema:  schema,
 		query:   query,
+
+		ctx: ctx,
+
+		FetchBufferSize:    defaultBufferSize,
+		ResponseBufferSize: defaultBufferSize,
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	if q.httpClient == nil {
+		q.httpClient = http.DefaultClient
 	}
 
-	err := q.postQuery()
+	if q.retryPolicy.Retryable == nil {
+		q.retryPolicy = DefaultRetryPolicy()
+	}
+
+	err := q.postQuery(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Immediately fetch one result set, to fill the column names.
-	err = q.fetchNext()
+	q.startPipeline()
+
+	// Immediately consume one decoded result, to fill the column names.
+	result, err := q.recv(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if result != nil {
+		q.applyResult(result)
+	} else {
+		q.closed = true
+	}
 
 	return q, nil
 }
 
+// SetDeadline sets the deadline for the entire lifetime of the query: once
+// it passes, any in-progress or future call to Next returns
+// context.DeadlineExceeded and the query is canceled. A zero value disables
+// the deadline.
+func (q *Query) SetDeadline(t time.Time) {
+	q.deadline.set(t)
+}
+
+// SetReadDeadline sets the deadline for the next call to Next. Unlike
+// SetDeadline, it only bounds a single read; it must be called again before
+// each subsequent call to Next that should be bounded.
+func (q *Query) SetReadDeadline(t time.Time) {
+	q.readDeadline.set(t)
+}
+
 // Columns returns a list of the column names for the query.
 func (q *Query) Columns() []string {
 	return q.columns
@@ -116,15 +263,25 @@ func (q *Query) Id() string {
 	return q.id
 }
 
-// Close closes the query, and cancels it if started.
+// Close closes the query, cancels it if still running, and closes the
+// channel returned by Stats. It's safe to call Close more than once, and
+// after the query has already been exhausted via Next.
 func (q *Query) Close() error {
-	if q.closed {
+	if q.fullyClosed {
 		return nil
 	}
-
+	q.fullyClosed = true
 	q.closed = true
+
+	q.drainPipeline()
+	close(q.statsCh)
+
+	if q.nextUri == "" {
+		return nil
+	}
+
 	req, _ := http.NewRequest("DELETE", q.nextUri, nil)
-	resp, err := q.makeRequest(req)
+	resp, err := q.makeRequest(context.Background(), req)
 	if err != nil {
 		return err
 	}
@@ -136,22 +293,50 @@ func (q *Query) Close() error {
 	return nil
 }
 
-// Next retrieves the next row from the dataset, fetching more if need be.
+// Next is equivalent to NextContext with context.Background().
 func (q *Query) Next() ([]interface{}, error) {
-	retry := initialRetry
+	return q.NextContext(context.Background())
+}
+
+// NextContext retrieves the next row from the dataset, fetching more if
+// need be. If ctx is canceled, or its deadline passes, before a row is
+// available, NextContext returns the corresponding error and the query is
+// canceled. If only the read deadline set by SetReadDeadline passes,
+// NextContext returns context.DeadlineExceeded for this call alone; the
+// query keeps running and a later call may still succeed.
+func (q *Query) NextContext(ctx context.Context) ([]interface{}, error) {
+	// The read deadline covers this call only.
+	defer q.readDeadline.set(time.Time{})
+
+	// Once the query's deadline has passed, every call fails.
+	select {
+	case <-q.deadline.doneCh():
+		if !q.closed {
+			q.closed = true
+			q.cancel()
+		}
+		return nil, context.DeadlineExceeded
+	default:
+	}
+
 	for !q.closed && len(q.bufferedRows) == 0 {
-		err := q.fetchNext()
+		result, err := q.recv(ctx)
 		if err != nil {
+			// A fired read deadline only fails this call, not the query
+			// itself, unless ctx is also done (in which case the query
+			// really is finished with).
+			if err != context.DeadlineExceeded || ctx.Err() != nil {
+				q.closed = true
+			}
 			return nil, err
 		}
 
-		if len(q.bufferedRows) == 0 {
-			time.Sleep(retry)
-			retry *= 2
-			if retry > maxRetry {
-				retry = maxRetry
-			}
+		if result == nil {
+			q.closed = true
+			break
 		}
+
+		q.applyResult(result)
 	}
 
 	if len(q.bufferedRows) > 0 {
@@ -163,10 +348,89 @@ func (q *Query) Next() ([]interface{}, error) {
 	}
 }
 
-func (q *Query) postQuery() error {
+// applyResult updates the query's state (buffered rows, columns, progress)
+// from a freshly decoded page of results.
+func (q *Query) applyResult(result *queryResult) {
+	q.bufferedRows = result.Data
+	q.state = result.Stats.State
+
+	if q.columns == nil && len(result.Columns) > 0 {
+		q.columns = make([]string, len(result.Columns))
+		q.columnTypes = make([]string, len(result.Columns))
+		for i, col := range result.Columns {
+			q.columns[i] = col.Name
+			q.columnTypes[i] = col.Type
+		}
+	}
+
+	q.progress = computeProgress(&result.Stats)
+	q.nextUri = result.NextUri
+}
+
+// computeProgress derives the proportion of splits completed from a page's
+// stats, or ProgressUnknown if presto hasn't scheduled the query yet.
+func computeProgress(stats *queryStats) float64 {
+	if !stats.Scheduled {
+		return ProgressUnknown
+	}
+	return float64(stats.CompletedSplits) / float64(stats.TotalSplits)
+}
+
+// recv waits for the next decoded result page from the pipeline, an error
+// from either the fetcher or the decoder, or ctx/the read deadline firing.
+// It returns a nil result (with a nil error) once the pipeline has finished
+// delivering all pages.
+//
+// Unlike the other cases, a fired read deadline only fails this call: it
+// doesn't cancel the query, since SetReadDeadline is documented to bound a
+// single read. It's disarmed here so it doesn't also fail every subsequent
+// call until SetReadDeadline is called again.
+func (q *Query) recv(ctx context.Context) (*queryResult, error) {
+	select {
+	case result, ok := <-q.responseCh:
+		if !ok {
+			return nil, nil
+		}
+		return result, nil
+	case err := <-q.errCh:
+		q.cancel()
+		// The error may come from a request that ctx aborted; report
+		// ctx's own error in that case.
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	case <-ctx.Done():
+		q.cancel()
+		return nil, ctx.Err()
+	case <-q.readDeadline.doneCh():
+		q.readDeadline.set(time.Time{})
+		return nil, context.DeadlineExceeded
+	}
+}
+
+// cancel stops the fetch-and-decode pipeline and issues a best-effort
+// cancel against presto's nextUri, using a background context since the
+// query's own context may already be done.
+func (q *Query) cancel() {
+	q.drainPipeline()
+
+	if q.nextUri == "" {
+		return
+	}
+
+	req, err := http.NewRequest("DELETE", q.nextUri, nil)
+	if err != nil {
+		return
+	}
+
+	q.makeRequest(context.Background(), req)
+}
+
+func (q *Query) postQuery(ctx context.Context) error {
 	queryUrl := fmt.Sprintf("%s/v1/statement", q.host)
 	req, _ := http.NewRequest("POST", queryUrl, strings.NewReader(q.query))
-	result, err := q.fetchResult(req)
+	result, err := q.fetchResult(ctx, req)
 	if err != nil {
 		q.closed = true
 		return err
@@ -177,44 +441,181 @@ func (q *Query) postQuery() error {
 	return nil
 }
 
-func (q *Query) fetchNext() error {
-	req, _ := http.NewRequest("GET", q.nextUri, nil)
-	result, err := q.fetchResult(req)
-	if err != nil {
-		q.closed = true
-		return err
+// startPipeline launches the fetcher and decoder goroutines that follow
+// nextUri and feed decoded result pages to Next.
+func (q *Query) startPipeline() {
+	q.doneCh = make(chan struct{})
+	q.fetchCh = make(chan *http.Response, q.FetchBufferSize)
+	q.responseCh = make(chan *queryResult, q.ResponseBufferSize)
+	q.nextUriCh = make(chan string, 1)
+	q.errCh = make(chan error, 1)
+	q.statsCh = make(chan QueryProgressInfo, 1)
+
+	pctx, cancel := context.WithCancel(q.ctx)
+	q.pipelineCancel = cancel
+
+	go q.fetchLoop(pctx)
+	go q.decodeLoop(pctx)
+	go q.watchDeadline(pctx)
+}
+
+// watchDeadline cancels pctx as soon as the query's deadline fires, so a
+// request that's blocked in httpClient.Do when the deadline passes is
+// aborted right away instead of running until fetchLoop happens to check
+// again between requests.
+func (q *Query) watchDeadline(pctx context.Context) {
+	select {
+	case <-pctx.Done():
+	case <-q.deadline.doneCh():
+		q.sendErr(context.DeadlineExceeded)
+		q.pipelineCancel()
 	}
+}
 
-	q.bufferedRows = result.Data
-	q.state = result.Stats.State
+// fetchLoop follows nextUri, pushing each response onto fetchCh for the
+// decoder to consume. It waits for the decoder to report the next uri
+// (extracted as part of decoding the page it just received) before issuing
+// the following request, so the fetch and decode of one page overlap with
+// Next serving rows from the pages before it.
+func (q *Query) fetchLoop(ctx context.Context) {
+	defer close(q.fetchCh)
+
+	uri := q.nextUri
+	for uri != "" {
+		select {
+		case <-q.doneCh:
+			return
+		case <-ctx.Done():
+			q.sendErr(ctx.Err())
+			return
+		case <-q.deadline.doneCh():
+			q.sendErr(context.DeadlineExceeded)
+			return
+		default:
+		}
 
-	if q.columns == nil && len(result.Columns) > 0 {
-		q.columns = make([]string, len(result.Columns))
-		for i, col := range result.Columns {
-			q.columns[i] = col.Name
+		req, _ := http.NewRequest("GET", uri, nil)
+		resp, err := q.makeRequest(ctx, req)
+		if err != nil {
+			q.sendErr(err)
+			return
+		}
+
+		select {
+		case q.fetchCh <- resp:
+		case <-q.doneCh:
+			resp.Body.Close()
+			return
+		}
+
+		select {
+		case uri = <-q.nextUriCh:
+		case <-q.doneCh:
+			return
 		}
 	}
+}
 
-	if result.Stats.Scheduled {
-		q.progress = float64(result.Stats.CompletedSplits) / float64(result.Stats.TotalSplits)
-	} else {
-		q.progress = ProgressUnknown
+// decodeLoop reads responses pushed by fetchLoop, decodes them into
+// queryResults, and pushes those onto responseCh for Next to consume. It
+// paces requests for empty, not-yet-complete pages with q.retryPolicy's
+// backoff (the same one makeRequest uses for retried requests), so
+// WithRetryPolicy governs both, and an in-progress query isn't polled in a
+// tight loop.
+func (q *Query) decodeLoop(ctx context.Context) {
+	defer close(q.responseCh)
+
+	attempt := 0
+	for resp := range q.fetchCh {
+		q.applySessionHeaders(resp)
+
+		result, err := q.readResult(resp)
+		if err != nil {
+			q.sendErr(err)
+			return
+		}
+
+		if result.Error.FailureInfo.Message != "" {
+			q.sendErr(fmt.Errorf("query failed: %s", result.Error.FailureInfo.Message))
+			return
+		}
+
+		q.sendStats(result)
+
+		if len(result.Data) == 0 && result.NextUri != "" {
+			select {
+			case <-time.After(q.retryPolicy.backoff(attempt)):
+			case <-q.doneCh:
+				return
+			case <-ctx.Done():
+				q.sendErr(ctx.Err())
+				return
+			case <-q.deadline.doneCh():
+				q.sendErr(context.DeadlineExceeded)
+				return
+			}
+
+			attempt++
+		} else {
+			attempt = 0
+		}
+
+		select {
+		case q.nextUriCh <- result.NextUri:
+		case <-q.doneCh:
+			return
+		}
+
+		select {
+		case q.responseCh <- result:
+		case <-q.doneCh:
+			return
+		}
 	}
+}
 
-	q.nextUri = result.NextUri
-	if result.NextUri == "" {
-		q.closed = true
+// sendErr delivers err to a waiting recv, if any, and tears down the
+// pipeline so the sibling goroutine doesn't leak waiting on a channel
+// nobody will read from again.
+func (q *Query) sendErr(err error) {
+	select {
+	case q.errCh <- err:
+	default:
 	}
 
-	return nil
+	q.doneOnce.Do(func() {
+		close(q.doneCh)
+	})
+}
+
+// drainPipeline signals the fetcher and decoder to stop, and drains
+// whatever they already had in flight, so neither goroutine leaks. It also
+// cancels the context passed to them, so a request blocked in
+// httpClient.Do is aborted rather than left to run to completion.
+func (q *Query) drainPipeline() {
+	q.doneOnce.Do(func() {
+		close(q.doneCh)
+	})
+
+	if q.pipelineCancel != nil {
+		q.pipelineCancel()
+	}
+
+	for resp := range q.fetchCh {
+		resp.Body.Close()
+	}
+	for range q.responseCh {
+	}
 }
 
-func (q *Query) fetchResult(req *http.Request) (*queryResult, error) {
-	resp, err := q.makeRequest(req)
+func (q *Query) fetchResult(ctx context.Context, req *http.Request) (*queryResult, error) {
+	resp, err := q.makeRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
+	q.applySessionHeaders(resp)
+
 	result, err := q.readResult(resp)
 	if err != nil {
 		return result, err
@@ -227,36 +628,102 @@ func (q *Query) fetchResult(req *http.Request) (*queryResult, error) {
 	return result, nil
 }
 
-func (q *Query) makeRequest(req *http.Request) (*http.Response, error) {
+func (q *Query) makeRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
 	req.Header.Add(userAgentHeader, userAgent)
 	req.Header.Add(userHeader, q.user)
 	req.Header.Add(catalogHeader, q.catalog)
 	req.Header.Add(schemaHeader, q.schema)
 	req.Header.Add(sourceHeader, q.source)
 
-	// Sometimes presto returns a 503 to indicate that results aren't yet
-	// available, and we should retry after waiting a bit.
-	retry := initialRetry
-	for {
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return nil, err
+	if v := sessionHeaderValue(q.sessionSnapshot()); v != "" {
+		req.Header.Set(sessionHeader, v)
+	}
+	if q.preparedName != "" {
+		req.Header.Set(preparedStatementHeader, q.preparedName+"="+url.QueryEscape(q.preparedSQL))
+	}
+
+	for attempt := 0; attempt < q.retryPolicy.MaxAttempts; attempt++ {
+		// A request with a body can only be sent once: req.Body is drained
+		// (and closed) by the previous attempt's Do, so every retry needs
+		// a fresh one rewound from GetBody, which http.NewRequest populates
+		// for any of the body types we use (e.g. strings.Reader).
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
 		}
 
-		if resp.StatusCode == 200 {
+		if q.auth != nil {
+			if err := q.auth.Authenticate(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := q.httpClient.Do(req)
+		if err == nil && resp.StatusCode == 200 {
 			return resp, nil
-		} else if resp.StatusCode != 503 {
+		}
+
+		if !q.retryPolicy.Retryable(resp, err) {
+			if err != nil {
+				return nil, err
+			}
 			return nil, fmt.Errorf("unexpected http status: %s", resp.Status)
 		}
 
-		time.Sleep(retry)
-		retry *= 2
-		if retry > maxRetry {
-			retry = maxRetry
+		q.recordRetry(resp)
+		if resp != nil {
+			resp.Body.Close()
 		}
+
+		if err := q.wait(ctx, q.retryPolicy.backoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("presto: giving up after %d attempts", q.retryPolicy.MaxAttempts)
+}
+
+// wait blocks for d, or until ctx or the query's deadline fires, recording
+// the actual time waited if metrics are enabled.
+func (q *Query) wait(ctx context.Context, d time.Duration) error {
+	start := time.Now()
+	defer func() {
+		if q.retryMetrics != nil {
+			q.retryMetrics.retryLatency.Observe(time.Since(start).Seconds())
+		}
+	}()
+
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.deadline.doneCh():
+		return context.DeadlineExceeded
 	}
 }
 
+// recordRetry updates the retry counters, if metrics are enabled. resp is
+// nil when the attempt failed with a network error rather than an HTTP
+// response.
+func (q *Query) recordRetry(resp *http.Response) {
+	if q.retryMetrics == nil {
+		return
+	}
+
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	q.retryMetrics.retriesTotal.Inc()
+	q.retryMetrics.retriesByCode.WithLabelValues(code).Inc()
+}
+
 func (q *Query) readResult(resp *http.Response) (*queryResult, error) {
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -264,8 +731,14 @@ func (q *Query) readResult(resp *http.Response) (*queryResult, error) {
 	}
 
 	result := queryResult{}
-	err = json.Unmarshal(body, &result)
-	if err != nil {
+
+	// Decode with UseNumber so bigint values (which routinely exceed
+	// float64's 2^53 safe-integer range) survive as json.Number instead of
+	// silently losing precision (and potentially flipping sign) by
+	// round-tripping through float64.
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
 		return nil, fmt.Errorf("error decoding json response from presto: %s", err)
 	}
 
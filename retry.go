@@ -0,0 +1,81 @@
+package presto
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how makeRequest retries a request against presto.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is tried,
+	// including the first attempt.
+	MaxAttempts int
+
+	// InitialBackoff and MaxBackoff bound the exponential backoff between
+	// attempts.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// JitterFactor adds up to JitterFactor*backoff of random jitter to each
+	// wait, to avoid many clients retrying in lockstep.
+	JitterFactor float64
+
+	// Retryable decides whether a given response or error should be
+	// retried. resp is nil if err is non-nil.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// DefaultRetryPolicy retries on HTTP 502, 503, and 504, and on network
+// errors reported as temporary, backing off from 50ms to 800ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: initialRetry,
+		MaxBackoff:     maxRetry,
+		JitterFactor:   0.2,
+		Retryable:      defaultRetryable,
+	}
+}
+
+func defaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return netErr.Temporary()
+		}
+		return false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns how long to wait before the next attempt, given how many
+// attempts (0-indexed) have already been made.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff << attempt
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if p.JitterFactor > 0 {
+		d += time.Duration(rand.Float64() * p.JitterFactor * float64(d))
+	}
+
+	return d
+}
+
+// WithRetryPolicy overrides the default retry policy used for every
+// request the query makes.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(q *Query) {
+		q.retryPolicy = p
+	}
+}
@@ -0,0 +1,108 @@
+package presto
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRetryPolicyRetryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	assert.True(t, p.Retryable(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.True(t, p.Retryable(&http.Response{StatusCode: http.StatusBadGateway}, nil))
+	assert.False(t, p.Retryable(&http.Response{StatusCode: http.StatusNotFound}, nil))
+}
+
+func TestRetryPolicyBackoffCapped(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: initialRetry, MaxBackoff: maxRetry}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		assert.LessOrEqual(t, p.backoff(attempt), maxRetry)
+	}
+}
+
+func TestWithMetricsRecordsRetries(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	q := &Query{}
+	WithMetrics(reg)(q)
+
+	q.recordRetry(&http.Response{StatusCode: http.StatusServiceUnavailable})
+
+	metrics, err := reg.Gather()
+	require.NoError(t, err)
+
+	var found bool
+	for _, mf := range metrics {
+		if mf.GetName() == "presto_client_retries_total" {
+			found = true
+			require.Len(t, mf.Metric, 1)
+			assert.Equal(t, float64(1), mf.Metric[0].GetCounter().GetValue())
+		}
+	}
+	assert.True(t, found, "presto_client_retries_total not registered")
+}
+
+func TestWithMetricsSharedAcrossQueries(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	q1 := &Query{}
+	q2 := &Query{}
+
+	assert.NotPanics(t, func() {
+		WithMetrics(reg)(q1)
+		WithMetrics(reg)(q2)
+	})
+
+	assert.Same(t, q1.retryMetrics, q2.retryMetrics)
+}
+
+func TestMakeRequestResendsPostBodyOnRetry(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if attempts == 1 {
+			assert.Equal(t, "select 1", string(body))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal(t, "select 1", string(body))
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "q1",
+			"nextUri": "",
+			"stats":   map[string]interface{}{"state": "FINISHED"},
+		}))
+	}))
+	defer server.Close()
+
+	q := &Query{
+		host:        server.URL,
+		retryPolicy: DefaultRetryPolicy(),
+		// A client without keep-alives never gets the transport's own
+		// automatic body-rewind behavior, so this exercises makeRequest's
+		// own rewind instead.
+		httpClient: &http.Client{Transport: &http.Transport{DisableKeepAlives: true}},
+	}
+
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader("select 1"))
+	require.NoError(t, err)
+
+	resp, err := q.makeRequest(context.Background(), req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,50 @@
+package presto
+
+import (
+	"database/sql/driver"
+	"io"
+)
+
+// Rows implements driver.Rows on top of a Query.
+type Rows struct {
+	q *Query
+}
+
+// Columns returns the names of the columns in the result set.
+func (r *Rows) Columns() []string {
+	return r.q.Columns()
+}
+
+// Close closes the underlying query.
+func (r *Rows) Close() error {
+	return r.q.Close()
+}
+
+// Next fetches the next row from the query and converts each value
+// according to its presto column type.
+func (r *Rows) Next(dest []driver.Value) error {
+	row, err := r.q.Next()
+	if err != nil {
+		return err
+	}
+
+	if row == nil {
+		return io.EOF
+	}
+
+	for i, raw := range row {
+		var prestoType string
+		if i < len(r.q.columnTypes) {
+			prestoType = r.q.columnTypes[i]
+		}
+
+		v, err := convertValue(raw, prestoType)
+		if err != nil {
+			return err
+		}
+
+		dest[i] = v
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package presto
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SetSession replaces the presto session properties sent, via the
+// X-Presto-Session header, with every request the query makes from then on:
+// later page fetches, PrepareContext, and queries run from a PreparedQuery.
+// The statement itself is posted inside NewQuery/NewQueryContext, before
+// SetSession can be called, so it does not carry these properties; use
+// WithSessionProperties to set the session the statement runs with.
+//
+// The session is kept up to date automatically as the coordinator reports
+// changes via the X-Presto-Set-Session and X-Presto-Clear-Session response
+// headers, so callers don't normally need to call SetSession again once a
+// query is running.
+func (q *Query) SetSession(props map[string]string) {
+	q.sessionMu.Lock()
+	defer q.sessionMu.Unlock()
+	q.session = props
+}
+
+// sessionSnapshot returns a copy of the query's current session properties,
+// safe to read concurrently with the fetcher/decoder goroutines.
+func (q *Query) sessionSnapshot() map[string]string {
+	q.sessionMu.Lock()
+	defer q.sessionMu.Unlock()
+
+	if len(q.session) == 0 {
+		return nil
+	}
+
+	snapshot := make(map[string]string, len(q.session))
+	for k, v := range q.session {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// WithSessionProperties sets the initial presto session properties for a
+// query, equivalent to calling SetSession before the query is run.
+func WithSessionProperties(props map[string]string) Option {
+	return func(q *Query) {
+		q.session = props
+	}
+}
+
+// sessionHeaderValue serializes props into the comma-separated
+// "key=value,key=value" form presto expects in the X-Presto-Session header.
+// Keys are sorted for deterministic output.
+func sessionHeaderValue(props map[string]string) string {
+	if len(props) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + props[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// applySessionHeaders updates the query's session from the
+// X-Presto-Set-Session and X-Presto-Clear-Session headers of resp, so
+// session changes made by the coordinator (e.g. in response to a "SET
+// SESSION" statement) are reflected in subsequent requests.
+func (q *Query) applySessionHeaders(resp *http.Response) {
+	set := resp.Header.Values(setSessionHeader)
+	cleared := resp.Header.Values(clearSessionHeader)
+	if len(set) == 0 && len(cleared) == 0 {
+		return
+	}
+
+	q.sessionMu.Lock()
+	defer q.sessionMu.Unlock()
+
+	if q.session == nil {
+		q.session = make(map[string]string)
+	}
+
+	for _, kv := range set {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			q.session[parts[0]] = parts[1]
+		}
+	}
+
+	for _, key := range cleared {
+		delete(q.session, key)
+	}
+}
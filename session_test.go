@@ -0,0 +1,100 @@
+package presto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHeaderRoundTrip(t *testing.T) {
+	var gotSession string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSession = r.Header.Get(sessionHeader)
+
+		w.Header().Set(setSessionHeader, "query_max_run_time=1h")
+		w.Header().Set(clearSessionHeader, "some_property")
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "q1",
+			"nextUri": "",
+			"stats":   map[string]interface{}{"state": "FINISHED"},
+		}))
+	}))
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "user", "test", "catalog", "schema", "SELECT 1",
+		WithSessionProperties(map[string]string{"some_property": "x"}))
+	require.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, "some_property=x", gotSession)
+	assert.Equal(t, map[string]string{"query_max_run_time": "1h"}, q.session)
+}
+
+// TestSessionConcurrentAccess exercises the documented race between the
+// pipeline's decodeLoop (which updates q.session from response headers as
+// pages arrive) and PrepareContext, issued concurrently from the caller's own
+// goroutine. Run with -race, this fails without sessionMu guarding every
+// access to q.session.
+func TestSessionConcurrentAccess(t *testing.T) {
+	const pages = 50
+	var n int32
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := atomic.AddInt32(&n, 1)
+		w.Header().Set(setSessionHeader, fmt.Sprintf("k=%d", i))
+		w.Header().Set("Content-Type", "application/json")
+
+		if i > pages {
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":    "q1",
+				"stats": map[string]interface{}{"state": "FINISHED"},
+			}))
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "q1",
+			"nextUri": server.URL,
+			"columns": []map[string]interface{}{{"name": "n", "type": "bigint"}},
+			"data":    [][]interface{}{{i}},
+		}))
+	}))
+	defer server.Close()
+
+	q, err := NewQuery(server.URL, "user", "test", "catalog", "schema", "SELECT n")
+	require.NoError(t, err)
+	defer q.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_, _ = q.PrepareContext(context.Background(), fmt.Sprintf("s%d", i), "SELECT 1")
+		}
+	}()
+
+	for {
+		row, err := q.Next()
+		require.NoError(t, err)
+		if row == nil {
+			break
+		}
+	}
+	wg.Wait()
+}
+
+func TestSessionHeaderValue(t *testing.T) {
+	assert.Equal(t, "", sessionHeaderValue(nil))
+	assert.Equal(t, "a=1,b=2", sessionHeaderValue(map[string]string{"b": "2", "a": "1"}))
+}
@@ -0,0 +1,51 @@
+package presto
+
+// QueryProgressInfo is a snapshot of a query's execution progress and
+// resource usage, as reported by presto alongside each page of results. See
+// Query.Stats.
+type QueryProgressInfo struct {
+	State    string
+	Progress float64
+
+	QueuedSplits    int
+	RunningSplits   int
+	CompletedSplits int
+	TotalSplits     int
+
+	ElapsedTimeMillis int64
+	CPUTimeMillis     int64
+	ProcessedRows     int64
+	ProcessedBytes    int64
+	PeakMemoryBytes   int64
+}
+
+// Stats returns a channel of QueryProgressInfo snapshots, sent as the
+// pipeline decodes each page of results. Sends are non-blocking: a slow or
+// absent consumer misses snapshots rather than stalling the pipeline. The
+// channel is closed when the query is closed.
+func (q *Query) Stats() <-chan QueryProgressInfo {
+	return q.statsCh
+}
+
+// sendStats delivers a progress snapshot for result to Stats, dropping it
+// if nobody's listening.
+func (q *Query) sendStats(result *queryResult) {
+	info := QueryProgressInfo{
+		State:             result.Stats.State,
+		Progress:          computeProgress(&result.Stats),
+		QueuedSplits:      result.Stats.QueuedSplits,
+		RunningSplits:     result.Stats.RunningSplits,
+		CompletedSplits:   result.Stats.CompletedSplits,
+		TotalSplits:       result.Stats.TotalSplits,
+		ElapsedTimeMillis: result.Stats.ElapsedTimeMillis,
+		CPUTimeMillis:     result.Stats.CPUTimeMillis,
+		ProcessedRows:     result.Stats.ProcessedRows,
+		ProcessedBytes:    result.Stats.ProcessedBytes,
+		PeakMemoryBytes:   result.Stats.PeakMemoryBytes,
+	}
+
+	select {
+	case q.statsCh <- info:
+	default:
+	}
+}
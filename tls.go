@@ -0,0 +1,30 @@
+package presto
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// sslClient builds an *http.Client that trusts the CA certificate(s) in the
+// PEM file at certPath, for use against a presto coordinator with a
+// certificate not signed by a public CA.
+func sslClient(certPath string) (*http.Client, error) {
+	pem, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("presto: reading ssl cert: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("presto: no certificates found in %s", certPath)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}